@@ -0,0 +1,287 @@
+package connstat
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tomasbasham/connstat/dnscache"
+)
+
+// TraceKey is a context.Context value key. Its associated value should be a
+// *Trace struct.
+type TraceKey struct{}
+
+// Trace contains a set of hooks for tracing events within a connection. Any
+// specific hook may be nil. Dialer itself only calls DNSCacheDone; it never
+// calls ConnectStart or ConnectDone (per-address connect events reach the
+// httptrace.ClientTrace hooks of the same name from net.Dialer instead).
+type Trace struct {
+	// ConnectStart is called before a Dial, excluding Dials made during DNS
+	// lookups. In the case of DualStack (Happy Eyeballs) dialing, this may
+	// be called multiple times, from multiple goroutines.
+	ConnectStart func(network, addr string)
+
+	// ConnectDone is called after a Dial with the results, excluding Dials
+	// made during DNS lookups. It may also be called multiple times, like
+	// ConnectStart.
+	ConnectDone func(network, addr string, err error)
+
+	// DNSCacheDone is called after a Dialer's DNSCache resolves host,
+	// reporting how long the lookup took and whether it was served from
+	// cache, coalesced with another in-flight lookup, or fell back to a
+	// stale answer.
+	DNSCacheDone func(host string, result dnscache.Result, duration time.Duration, err error)
+}
+
+// WithClientTrace returns a new context based on ctx that carries trace.
+func WithClientTrace(ctx context.Context, trace *Trace) context.Context {
+	if trace == nil {
+		panic("nil trace")
+	}
+
+	return context.WithValue(ctx, TraceKey{}, trace)
+}
+
+func contextTrace(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(TraceKey{}).(*Trace)
+	return trace
+}
+
+// HappyEyeballs configures RFC 8305 dual-stack dial racing for a Dialer.
+// The zero value leaves net.Dialer's own defaults in place.
+type HappyEyeballs struct {
+	// FallbackDelay is how long to wait for a connection attempt on a
+	// preferred address family before starting one on a secondary family.
+	// Zero uses net.Dialer's default of 300ms; a negative value disables
+	// the fallback entirely, so addresses are tried one after another.
+	FallbackDelay time.Duration
+
+	// DualStack opts into RFC 6555 Fast Fallback dialing. It is carried
+	// straight through to the deprecated net.Dialer.DualStack field, which
+	// Go has treated as always-on since 1.12, but is kept here so callers
+	// can express the setting explicitly rather than relying on defaults.
+	DualStack bool
+}
+
+// apply copies cfg onto d.
+func (cfg HappyEyeballs) apply(d *net.Dialer) {
+	d.FallbackDelay = cfg.FallbackDelay
+	d.DualStack = cfg.DualStack //nolint:staticcheck // explicit opt-in knob, see HappyEyeballs doc
+}
+
+// happyEyeballsKey is the context.Context value key for a per-probe
+// HappyEyeballs override.
+type happyEyeballsKey struct{}
+
+// WithHappyEyeballs returns a new context based on ctx that carries cfg.
+// DialContext reads this back and applies it to a private copy of the
+// Dialer's net.Dialer, rather than mutating shared state, so that a single
+// Dialer can safely be reused across concurrent probes with different
+// settings.
+func WithHappyEyeballs(ctx context.Context, cfg HappyEyeballs) context.Context {
+	return context.WithValue(ctx, happyEyeballsKey{}, cfg)
+}
+
+func contextHappyEyeballs(ctx context.Context) (HappyEyeballs, bool) {
+	cfg, ok := ctx.Value(happyEyeballsKey{}).(HappyEyeballs)
+	return cfg, ok
+}
+
+// Dialer wraps net.Dialer, optionally resolving hostnames through a
+// caching DNSCache instead of net.Dialer's own resolution.
+type Dialer struct {
+	*net.Dialer
+
+	// DNSCache, if non-nil, resolves hostnames through a caching,
+	// singleflight-coalescing resolver instead of net.Dialer's own
+	// resolution, and is consulted by DialContext. Repeated probes against
+	// the same host then reuse cached A/AAAA results rather than hitting
+	// the network every time.
+	DNSCache *dnscache.Resolver
+}
+
+// DialContext dials address, which may be a hostname or literal IP, over
+// network. Its behaviour is the same on every call regardless of what
+// other DialContext calls are in flight, so a single Dialer can safely be
+// shared across concurrent probes.
+//
+// If the context carries a HappyEyeballs override (see WithHappyEyeballs),
+// it is applied to a private copy of the Dialer's net.Dialer rather than
+// mutated in place. If d.DNSCache is set and address names a host, it is
+// resolved through the cache and each returned address is tried in turn
+// until one succeeds; otherwise this falls through to net.Dialer's own
+// resolution (and, for multiple addresses, its own Happy Eyeballs racing).
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := d.Dialer
+	if cfg, ok := contextHappyEyeballs(ctx); ok {
+		clone := *d.Dialer
+		cfg.apply(&clone)
+		dialer = &clone
+	}
+
+	if d.DNSCache == nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	start := time.Now()
+	result, err := d.DNSCache.Lookup(ctx, host)
+	duration := time.Since(start)
+
+	if trace := contextTrace(ctx); trace != nil && trace.DNSCacheDone != nil {
+		trace.DNSCacheDone(host, result, duration, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Addrs) == 0 {
+		return nil, fmt.Errorf("connstat: no addresses found for %s", host)
+	}
+
+	var lastErr error
+	for _, addr := range result.Addrs {
+		conn, dialErr := dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+		if dialErr == nil {
+			return conn, nil
+		}
+		lastErr = dialErr
+	}
+
+	return nil, lastErr
+}
+
+// DialPacket resolves address (host:port, where host may be a hostname or a
+// literal IP) for UDP use, consulting DNSCache the same way DialContext
+// does, and returns a local PacketConn alongside the resolved remote
+// address. This is QUIC's analogue of DialContext: QUIC runs over UDP, so
+// there is no net.Dialer.DialContext to delegate to, and no Happy Eyeballs
+// racing across resolved addresses (the first address is used directly).
+func (d *Dialer) DialPacket(ctx context.Context, network, address string) (net.PacketConn, net.Addr, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		if d.DNSCache != nil {
+			start := time.Now()
+			result, lookupErr := d.DNSCache.Lookup(ctx, host)
+			duration := time.Since(start)
+
+			if trace := contextTrace(ctx); trace != nil && trace.DNSCacheDone != nil {
+				trace.DNSCacheDone(host, result, duration, lookupErr)
+			}
+
+			if lookupErr != nil {
+				return nil, nil, lookupErr
+			}
+			if len(result.Addrs) == 0 {
+				return nil, nil, fmt.Errorf("connstat: no addresses found for %s", host)
+			}
+			ip = result.Addrs[0].IP
+		} else {
+			addrs, lookupErr := net.DefaultResolver.LookupIPAddr(ctx, host)
+			if lookupErr != nil {
+				return nil, nil, lookupErr
+			}
+			if len(addrs) == 0 {
+				return nil, nil, fmt.Errorf("connstat: no addresses found for %s", host)
+			}
+			ip = addrs[0].IP
+		}
+	}
+
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	udpNetwork := network
+	if ip.To4() == nil {
+		udpNetwork = "udp6"
+	} else if network == "udp" {
+		udpNetwork = "udp4"
+	}
+
+	conn, err := net.ListenUDP(udpNetwork, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, &net.UDPAddr{IP: ip, Port: portNum}, nil
+}
+
+// connectTracker accumulates one ConnectAttempt per (network, addr) dial
+// attempt. net.Dialer.DialContext races one such attempt per resolved
+// address when Happy Eyeballs dual-stack dialing is in effect, calling
+// ConnectStart/ConnectDone concurrently from multiple goroutines, so access
+// is guarded by a mutex.
+type connectTracker struct {
+	mu       sync.Mutex
+	base     time.Time
+	starts   map[string]time.Time
+	attempts []ConnectAttempt
+}
+
+func newConnectTracker(base time.Time) *connectTracker {
+	return &connectTracker{base: base, starts: make(map[string]time.Time)}
+}
+
+func (t *connectTracker) connectStart(network, addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.starts[network+" "+addr] = time.Now()
+}
+
+func (t *connectTracker) connectDone(network, addr string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, ok := t.starts[network+" "+addr]
+	if !ok {
+		start = time.Now()
+	}
+
+	attempt := ConnectAttempt{
+		Network:   network,
+		Address:   addr,
+		Operation: FormatDuration(time.Since(start)),
+		Total:     FormatDuration(time.Since(t.base)),
+	}
+	if err != nil {
+		attempt.Err = err.Error()
+	}
+
+	t.attempts = append(t.attempts, attempt)
+}
+
+// result returns every recorded attempt, and the Timings of the winning
+// (error-free) attempt if one completed.
+func (t *connectTracker) result() ([]ConnectAttempt, Timings) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var winner Timings
+	for _, attempt := range t.attempts {
+		if attempt.Err == "" {
+			winner = Timings{Operation: attempt.Operation, Total: attempt.Total}
+		}
+	}
+
+	return t.attempts, winner
+}
+
+// NewDialer returns a Dialer ready for use.
+func NewDialer() *Dialer {
+	return &Dialer{Dialer: &net.Dialer{}}
+}
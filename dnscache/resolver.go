@@ -0,0 +1,190 @@
+// Package dnscache implements a minimal caching DNS resolver, inspired by
+// Tailscale's dnscache package. It wraps a *net.Resolver, coalescing
+// concurrent lookups for the same hostname via singleflight, caching
+// answers for a configurable TTL, and falling back to the last good answer
+// if a subsequent lookup fails.
+package dnscache
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Result reports the outcome of a single Resolver.Lookup call.
+type Result struct {
+	// Addrs holds every address returned for the host, in the order the
+	// resolver returned them.
+	Addrs []net.IPAddr
+
+	// V4 and V6 are Addrs split by address family.
+	V4 []net.IPAddr
+	V6 []net.IPAddr
+
+	// CacheHit reports whether Addrs came from a cache entry still within
+	// its TTL, rather than a fresh lookup.
+	CacheHit bool
+
+	// Coalesced reports whether this call shared the result of a lookup
+	// already in flight for the same host, rather than triggering its own.
+	Coalesced bool
+
+	// StaleUsed reports whether the upstream lookup failed and Addrs was
+	// served from an expired cache entry as a fallback.
+	StaleUsed bool
+}
+
+type entry struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// Resolver is a caching, singleflight-coalescing wrapper around a
+// *net.Resolver. The zero value is ready to use.
+type Resolver struct {
+	// Resolver performs the underlying DNS lookups. Defaults to
+	// net.DefaultResolver.
+	Resolver *net.Resolver
+
+	// TTL controls how long a successful answer is cached. Zero disables
+	// caching: every call still coalesces concurrent lookups, but each
+	// uncoalesced call hits the network.
+	TTL time.Duration
+
+	// LookupTimeout bounds each underlying lookup. Zero means only the
+	// caller's context deadline applies.
+	LookupTimeout time.Duration
+
+	// Hosts is a bootstrap list of statically-known hostname -> IP address
+	// mappings, consulted before any cache entry or network lookup. It
+	// must not be mutated once the Resolver is in use.
+	Hosts map[string][]string
+
+	mu    sync.Mutex
+	cache map[string]entry
+	sf    group
+}
+
+// Lookup resolves host, preferring (in order) a bootstrap entry, a
+// still-fresh cache entry, a lookup already in flight for host, and
+// finally a fresh call to Resolver.LookupIPAddr. If the fresh call fails
+// and a previous (possibly expired) answer is cached, that stale answer is
+// returned instead of the error.
+func (r *Resolver) Lookup(ctx context.Context, host string) (Result, error) {
+	if addrs, ok := r.bootstrap(host); ok {
+		return newResult(addrs, false, false, false), nil
+	}
+
+	if addrs, ok := r.fresh(host); ok {
+		return newResult(addrs, true, false, false), nil
+	}
+
+	v, shared, err := r.sf.do(host, func() (any, error) {
+		return r.lookup(ctx, host)
+	})
+
+	if err != nil {
+		if addrs, ok := r.stale(host); ok {
+			return newResult(addrs, false, shared, true), nil
+		}
+		return Result{}, err
+	}
+
+	addrs := v.([]net.IPAddr)
+	r.store(host, addrs)
+
+	return newResult(addrs, false, shared, false), nil
+}
+
+func (r *Resolver) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	if r.LookupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.LookupTimeout)
+		defer cancel()
+	}
+
+	return resolver.LookupIPAddr(ctx, host)
+}
+
+func (r *Resolver) bootstrap(host string) ([]net.IPAddr, bool) {
+	ips, ok := r.Hosts[host]
+	if !ok {
+		return nil, false
+	}
+
+	addrs := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed != nil {
+			addrs = append(addrs, net.IPAddr{IP: parsed})
+		}
+	}
+
+	return addrs, len(addrs) > 0
+}
+
+func (r *Resolver) fresh(host string) ([]net.IPAddr, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.cache[host]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+
+	return e.addrs, true
+}
+
+func (r *Resolver) stale(host string) ([]net.IPAddr, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.cache[host]
+	if !ok {
+		return nil, false
+	}
+
+	return e.addrs, true
+}
+
+func (r *Resolver) store(host string, addrs []net.IPAddr) {
+	if r.TTL <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cache == nil {
+		r.cache = make(map[string]entry)
+	}
+	r.cache[host] = entry{addrs: addrs, expires: time.Now().Add(r.TTL)}
+}
+
+func newResult(addrs []net.IPAddr, cacheHit, coalesced, staleUsed bool) Result {
+	v4, v6 := splitByFamily(addrs)
+	return Result{
+		Addrs:     addrs,
+		V4:        v4,
+		V6:        v6,
+		CacheHit:  cacheHit,
+		Coalesced: coalesced,
+		StaleUsed: staleUsed,
+	}
+}
+
+func splitByFamily(addrs []net.IPAddr) (v4, v6 []net.IPAddr) {
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			v4 = append(v4, addr)
+		} else {
+			v6 = append(v6, addr)
+		}
+	}
+	return v4, v6
+}
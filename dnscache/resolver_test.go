@@ -0,0 +1,145 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func ipAddrs(ips ...string) []net.IPAddr {
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs
+}
+
+func TestResolverBootstrap(t *testing.T) {
+	r := &Resolver{Hosts: map[string][]string{"example.com": {"10.0.0.1"}}}
+
+	result, err := r.Lookup(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if result.CacheHit || result.Coalesced || result.StaleUsed {
+		t.Fatalf("result = %+v, want all flags false for a bootstrap hit", result)
+	}
+	if len(result.Addrs) != 1 || result.Addrs[0].String() != "10.0.0.1" {
+		t.Fatalf("Addrs = %v, want [10.0.0.1]", result.Addrs)
+	}
+}
+
+func TestResolverCachesWithinTTL(t *testing.T) {
+	r := &Resolver{TTL: time.Minute}
+	r.store("example.com", ipAddrs("10.0.0.1"))
+
+	result, ok := r.fresh("example.com")
+	if !ok {
+		t.Fatal("fresh: want cache hit after store, got miss")
+	}
+	if len(result) != 1 {
+		t.Fatalf("len(result) = %d, want 1", len(result))
+	}
+}
+
+func TestResolverExpiresAfterTTL(t *testing.T) {
+	r := &Resolver{TTL: time.Nanosecond}
+	r.store("example.com", ipAddrs("10.0.0.1"))
+
+	time.Sleep(time.Millisecond)
+
+	if _, ok := r.fresh("example.com"); ok {
+		t.Fatal("fresh: want cache miss once TTL has elapsed")
+	}
+	if _, ok := r.stale("example.com"); !ok {
+		t.Fatal("stale: want the expired entry still available as a fallback")
+	}
+}
+
+func TestResolverZeroTTLDoesNotCache(t *testing.T) {
+	r := &Resolver{}
+	r.store("example.com", ipAddrs("10.0.0.1"))
+
+	if _, ok := r.fresh("example.com"); ok {
+		t.Fatal("fresh: want no cache entry when TTL is zero")
+	}
+}
+
+func TestResolverCoalescesConcurrentLookups(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+
+	r := &Resolver{}
+
+	lookup := func() (any, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return ipAddrs("10.0.0.1"), nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]bool, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, shared, err := r.sf.do("example.com", lookup)
+			if err != nil {
+				t.Errorf("do: %v", err)
+			}
+			results[i] = shared
+		}(i)
+	}
+
+	// Give every goroutine a chance to register before releasing the call.
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls = %d, want 1 (all concurrent lookups coalesced)", got)
+	}
+
+	shared := 0
+	for _, s := range results {
+		if s {
+			shared++
+		}
+	}
+	if shared != len(results)-1 {
+		t.Fatalf("shared = %d, want %d (only the first caller is not shared)", shared, len(results)-1)
+	}
+}
+
+func TestResolverStaleFallbackOnLookupError(t *testing.T) {
+	r := &Resolver{TTL: time.Nanosecond}
+	r.store("example.com", ipAddrs("10.0.0.1"))
+	time.Sleep(time.Millisecond)
+
+	wantErr := errors.New("lookup failed")
+	v, shared, err := r.sf.do("example.com", func() (any, error) { return nil, wantErr })
+	if err != wantErr || v != nil || shared {
+		t.Fatalf("sf.do = (%v, %v, %v), want (nil, false, %v)", v, shared, err, wantErr)
+	}
+
+	addrs, ok := r.stale("example.com")
+	if !ok || len(addrs) != 1 {
+		t.Fatalf("stale = (%v, %v), want the cached entry to still be available", addrs, ok)
+	}
+}
+
+func TestSplitByFamily(t *testing.T) {
+	addrs := ipAddrs("10.0.0.1", "192.168.1.1", "::1", "2001:db8::1")
+	v4, v6 := splitByFamily(addrs)
+
+	if len(v4) != 2 {
+		t.Fatalf("len(v4) = %d, want 2", len(v4))
+	}
+	if len(v6) != 2 {
+		t.Fatalf("len(v6) = %d, want 2", len(v6))
+	}
+}
@@ -0,0 +1,48 @@
+package dnscache
+
+import "sync"
+
+// group coalesces concurrent calls for the same key into a single
+// execution of fn, as golang.org/x/sync/singleflight does. It is
+// reimplemented here, minimally, to avoid pulling in the dependency for a
+// handful of lines.
+type group struct {
+	mu sync.Mutex
+	m  map[string]*call
+}
+
+type call struct {
+	wg  sync.WaitGroup
+	val any
+	err error
+}
+
+// do executes fn for key, or waits for and returns the result of an
+// in-flight call for the same key. shared reports whether the caller
+// received the result of a call made on its behalf by another goroutine.
+func (g *group) do(key string, fn func() (any, error)) (val any, shared bool, err error) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+
+	if c, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, true, c.err
+	}
+
+	c := new(call)
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = fn()
+	c.wg.Done()
+
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+
+	return c.val, false, c.err
+}
@@ -0,0 +1,115 @@
+package dnscache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupDoReturnsResult(t *testing.T) {
+	var g group
+
+	v, shared, err := g.do("key", func() (any, error) { return 42, nil })
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	if shared {
+		t.Fatal("shared = true, want false for an uncontended call")
+	}
+	if v != 42 {
+		t.Fatalf("v = %v, want 42", v)
+	}
+}
+
+func TestGroupDoPropagatesError(t *testing.T) {
+	var g group
+	wantErr := errors.New("boom")
+
+	_, _, err := g.do("key", func() (any, error) { return nil, wantErr })
+	if err != wantErr {
+		t.Fatalf("err = %v, want %v", err, wantErr)
+	}
+}
+
+func TestGroupDoDeletesEntryAfterCompletion(t *testing.T) {
+	var g group
+
+	if _, _, err := g.do("key", func() (any, error) { return 1, nil }); err != nil {
+		t.Fatalf("do: %v", err)
+	}
+
+	g.mu.Lock()
+	_, pending := g.m["key"]
+	g.mu.Unlock()
+	if pending {
+		t.Fatal("key still tracked as in-flight after do returned")
+	}
+}
+
+func TestGroupDoCoalescesConcurrentCallers(t *testing.T) {
+	var g group
+	var calls int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	shared := make([]bool, 10)
+	for i := range shared {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, s, err := g.do("key", func() (any, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return nil, nil
+			})
+			if err != nil {
+				t.Errorf("do: %v", err)
+			}
+			shared[i] = s
+		}(i)
+	}
+
+	// Give every goroutine a chance to register as waiting on the in-flight
+	// call before releasing it.
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls = %d, want 1", got)
+	}
+
+	notShared := 0
+	for _, s := range shared {
+		if !s {
+			notShared++
+		}
+	}
+	if notShared != 1 {
+		t.Fatalf("callers reporting shared=false = %d, want exactly 1", notShared)
+	}
+}
+
+func TestGroupDoDifferentKeysDoNotCoalesce(t *testing.T) {
+	var g group
+	var calls int32
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			_, _, _ = g.do(key, func() (any, error) {
+				atomic.AddInt32(&calls, 1)
+				return nil, nil
+			})
+		}(key)
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("calls = %d, want 3 (distinct keys must not coalesce)", got)
+	}
+}
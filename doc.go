@@ -0,0 +1,5 @@
+// Package connstat implements connection diagnostics for HTTP(S) endpoints,
+// in the spirit of curl's -w timing output. A Prober performs a single
+// request and reports how long each phase (DNS lookup, TCP connect, TLS
+// handshake, first byte) took, alongside the response itself.
+package connstat
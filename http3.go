@@ -0,0 +1,149 @@
+package connstat
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// probeHTTP3 performs req entirely over HTTP/3 (QUIC), bypassing the usual
+// net/http.Transport and its TCP Dialer: QUIC runs over UDP, so there is no
+// TCP connect or TLS handshake phase in the usual sense, and httptrace's
+// hooks never fire over a QUIC connection. Instead the QUIC handshake
+// (which folds connection establishment and the TLS 1.3 handshake into a
+// single round trip, or zero round trips when resuming with 0-RTT) is timed
+// directly around the dial, and reported as TestResults.QUICHandshake.
+func (p *Prober) probeHTTP3(ctx context.Context, req *Request) (*TestResults, error) {
+	return p.probeHTTP3Addr(ctx, req, "")
+}
+
+// probeHTTP3Addr is probeHTTP3, but if dialAddr is non-empty it is dialed in
+// place of the request URL's own host:port. This lets an Alt-Svc upgrade
+// (see Probe) connect to the advertised alternate authority while still
+// sending the original Host in the request.
+func (p *Prober) probeHTTP3Addr(ctx context.Context, req *Request, dialAddr string) (*TestResults, error) {
+	var results TestResults
+	probeStart := time.Now()
+
+	target, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	results.Host = target.Hostname()
+
+	roundTripper := &http3.RoundTripper{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: req.Insecure},
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			if dialAddr != "" {
+				addr = dialAddr
+			}
+
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				host, port = addr, "443"
+			}
+
+			pconn, remote, err := p.Dialer.DialPacket(ctx, "udp", net.JoinHostPort(host, port))
+			if err != nil {
+				return nil, err
+			}
+
+			handshakeStart := time.Now()
+			conn, err := quic.DialEarly(ctx, pconn, remote, tlsCfg, cfg)
+			results.QUICHandshake.Operation = FormatDuration(time.Since(handshakeStart))
+			results.QUICHandshake.Total = FormatDuration(time.Since(probeStart))
+			if err != nil {
+				return nil, err
+			}
+
+			results.QUICZeroRTT = conn.ConnectionState().Used0RTT
+			return conn, nil
+		},
+	}
+	defer roundTripper.Close()
+
+	httpReq, err := req.httpRequest(ctx)
+	if err != nil {
+		return &results, err
+	}
+
+	client := &http.Client{
+		Transport: roundTripper,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			if !req.FollowRedirects {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+	roundTripStart := time.Now()
+	res, err := client.Do(httpReq)
+	if err != nil {
+		results.Error = err.Error()
+		return &results, err
+	}
+	defer res.Body.Close()
+
+	results.FirstByte = FormatDuration(time.Since(roundTripStart))
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		results.Error = err.Error()
+		return &results, err
+	}
+	results.Total = FormatDuration(time.Since(probeStart))
+
+	response := &Response{
+		Status:        res.Status,
+		Protocol:      res.Proto,
+		ContentLength: res.ContentLength,
+		ContentType:   res.Header.Get("Content-Type"),
+		Body:          body,
+	}
+	if res.TLS != nil {
+		response.TLS = &TLS{
+			Version:            res.TLS.Version,
+			HandshakeComplete:  res.TLS.HandshakeComplete,
+			CipherSuite:        tls.CipherSuiteName(res.TLS.CipherSuite),
+			NegotiatedProtocol: res.TLS.NegotiatedProtocol,
+		}
+	}
+	results.Response = response
+
+	return &results, nil
+}
+
+// altSvcH3Authority looks for an "h3" entry in header (an Alt-Svc response
+// header value, per RFC 7838) and, if found, returns the host:port it
+// advertises, ready to dial. A host-less entry (e.g. `h3=":443"`) means the
+// alternate service lives on originHost at the given port.
+func altSvcH3Authority(header, originHost string) (string, bool) {
+	for _, entry := range strings.Split(header, ",") {
+		params := strings.Split(strings.TrimSpace(entry), ";")
+		protocol, quoted, ok := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !ok || protocol != "h3" {
+			continue
+		}
+
+		authority := strings.Trim(strings.TrimSpace(quoted), `"`)
+		host, port, err := net.SplitHostPort(authority)
+		if err != nil {
+			continue
+		}
+		if host == "" {
+			host = originHost
+		}
+
+		return net.JoinHostPort(host, port), true
+	}
+
+	return "", false
+}
@@ -0,0 +1,234 @@
+package connstat
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+
+	"github.com/tomasbasham/connstat/dnscache"
+)
+
+// Prober performs connection probes against a target and reports timing and
+// response information for each phase of the request.
+type Prober struct {
+	// Dialer establishes the underlying TCP connection for each probe.
+	Dialer *Dialer
+}
+
+// New returns a Prober ready for use, with a fresh Dialer.
+func New() *Prober {
+	return &Prober{Dialer: NewDialer()}
+}
+
+// Probe performs req against its target and returns the resulting timings
+// and response. If the request was sent but failed (e.g. a connection or
+// TLS error), Probe returns a non-nil TestResults with Error set alongside
+// the error.
+func (p *Prober) Probe(ctx context.Context, req *Request) (*TestResults, error) {
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+
+	if isWebSocketURL(req.URL) {
+		return p.probeWebSocket(ctx, req)
+	}
+
+	if req.HTTP3 {
+		return p.probeHTTP3(ctx, req)
+	}
+
+	if req.HappyEyeballs != nil {
+		ctx = WithHappyEyeballs(ctx, *req.HappyEyeballs)
+	}
+
+	var results TestResults
+	var roundTripTime, lookupTime, handshakeTime time.Time
+	var tracker *connectTracker
+
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			roundTripTime = time.Now()
+			tracker = newConnectTracker(roundTripTime)
+		},
+		DNSStart: func(dnsInfo httptrace.DNSStartInfo) {
+			results.Host = dnsInfo.Host
+			lookupTime = time.Now()
+		},
+		DNSDone: func(dnsInfo httptrace.DNSDoneInfo) {
+			results.DNSLookup.Operation = FormatDuration(time.Since(lookupTime))
+			results.DNSLookup.Total = FormatDuration(time.Since(roundTripTime))
+			results.Addresses = mapS(dnsInfo.Addrs, func(addr net.IPAddr) string { return addr.String() })
+		},
+		ConnectStart: func(network, addr string) {
+			tracker.connectStart(network, addr)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			tracker.connectDone(network, addr, err)
+
+			if err != nil {
+				results.Error = err.Error()
+			}
+		},
+		TLSHandshakeStart: func() {
+			handshakeTime = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			results.TLSHandshake.Operation = FormatDuration(time.Since(handshakeTime))
+			results.TLSHandshake.Total = FormatDuration(time.Since(roundTripTime))
+
+			if err != nil {
+				results.Error = err.Error()
+			}
+		},
+		GotConn: func(_ httptrace.GotConnInfo) {
+			results.Total = FormatDuration(time.Since(roundTripTime))
+		},
+		GotFirstResponseByte: func() {
+			results.FirstByte = FormatDuration(time.Since(roundTripTime))
+		},
+	}
+
+	httpReq, err := req.httpRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reqCtx := httptrace.WithClientTrace(httpReq.Context(), trace)
+	if p.Dialer.DNSCache != nil {
+		reqCtx = WithClientTrace(reqCtx, &Trace{
+			DNSCacheDone: func(host string, result dnscache.Result, duration time.Duration, err error) {
+				results.Host = host
+				results.DNSLookup.Operation = FormatDuration(duration)
+				results.DNSLookup.Total = FormatDuration(time.Since(roundTripTime))
+				results.Addresses = mapS(result.Addrs, func(addr net.IPAddr) string { return addr.String() })
+				results.DNSCacheHit = result.CacheHit
+				results.DNSCoalesced = result.Coalesced
+				results.DNSStaleUsed = result.StaleUsed
+
+				if err != nil {
+					results.Error = err.Error()
+				}
+			},
+		})
+	}
+	httpReq = httpReq.WithContext(reqCtx)
+
+	defer func() {
+		if tracker != nil {
+			results.ConnectAttempts, results.Connect = tracker.result()
+		}
+	}()
+
+	transport := &http.Transport{
+		DialContext: p.Dialer.DialContext,
+	}
+	if req.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	if req.HTTP2 {
+		transport.ForceAttemptHTTP2 = true
+	}
+
+	if req.ProxyURL != "" {
+		proxyURL, err := url.Parse(req.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.DialContext = p.proxyDialContext(proxyURL, req.Insecure, func(dial, connect Timings, err error) {
+			results.ProxyDial = dial
+			results.ProxyConnect = connect
+
+			if err != nil {
+				results.Error = err.Error()
+			}
+		})
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			if !req.FollowRedirects {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+
+	res, err := client.Do(httpReq)
+	if err != nil {
+		if results.Error == "" {
+			results.Error = err.Error()
+		}
+		return &results, err
+	}
+	defer res.Body.Close()
+
+	if req.HTTP2 && res.ProtoMajor != 2 {
+		err = fmt.Errorf("connstat: server did not negotiate HTTP/2, got %s", res.Proto)
+		results.Error = err.Error()
+		return &results, err
+	}
+
+	altSvc := res.Header.Get("Alt-Svc")
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		results.Error = err.Error()
+		return &results, err
+	}
+
+	response := &Response{
+		Status:        res.Status,
+		Protocol:      res.Proto,
+		ContentLength: res.ContentLength,
+		ContentType:   res.Header.Get("Content-Type"),
+		Body:          body,
+	}
+	if res.TLS != nil {
+		response.TLS = &TLS{
+			Version:            res.TLS.Version,
+			HandshakeComplete:  res.TLS.HandshakeComplete,
+			CipherSuite:        tls.CipherSuiteName(res.TLS.CipherSuite),
+			NegotiatedProtocol: res.TLS.NegotiatedProtocol,
+		}
+	}
+	results.Response = response
+
+	if req.AltSvc && altSvc != "" {
+		results.AltSvcAdvertised = altSvc
+
+		if altAddr, ok := altSvcH3Authority(altSvc, httpReq.URL.Hostname()); ok {
+			httpsURL := *httpReq.URL
+			httpsURL.Scheme = "https"
+
+			upgraded := *req
+			upgraded.URL = httpsURL.String()
+			upgraded.HTTP3 = true
+
+			if upgradedResults, upgradedErr := p.probeHTTP3Addr(ctx, &upgraded, altAddr); upgradedErr == nil {
+				upgradedResults.AltSvcAdvertised = altSvc
+				upgradedResults.AltSvcUpgraded = true
+				return upgradedResults, nil
+			}
+		}
+	}
+
+	return &results, nil
+}
+
+func mapS[T any, U any](s []T, f func(T) U) []U {
+	ret := make([]U, len(s))
+	for i, v := range s {
+		ret[i] = f(v)
+	}
+	return ret
+}
@@ -0,0 +1,63 @@
+package connstat
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeHTTPSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	p := New()
+	results, err := p.Probe(context.Background(), &Request{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("Probe: %v", err)
+	}
+	if results.Error != "" {
+		t.Fatalf("results.Error = %q, want empty", results.Error)
+	}
+	if results.Response == nil || results.Response.Status != "200 OK" {
+		t.Fatalf("Response = %+v, want status 200 OK", results.Response)
+	}
+	if string(results.Response.Body) != "hello" {
+		t.Fatalf("Response.Body = %q, want %q", results.Response.Body, "hello")
+	}
+}
+
+func TestProbeHTTPConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // nothing is listening on addr now
+
+	p := New()
+	results, err := p.Probe(context.Background(), &Request{URL: "http://" + addr})
+	if err == nil {
+		t.Fatal("Probe: want error for a refused connection, got nil")
+	}
+	if results == nil {
+		t.Fatal("results = nil, want a non-nil TestResults with Error set")
+	}
+	if results.Error == "" {
+		t.Fatalf("results.Error = %q, want a non-empty error", results.Error)
+	}
+}
+
+func TestFormatDurationMarshalJSON(t *testing.T) {
+	got, err := FormatDuration(1500000).MarshalJSON() // 1.5ms in nanoseconds
+	if err != nil {
+		t.Fatalf("MarshalJSON: %v", err)
+	}
+	if string(got) != `"1.5ms"` {
+		t.Fatalf("MarshalJSON = %s, want %q", got, `"1.5ms"`)
+	}
+}
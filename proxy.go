@@ -0,0 +1,252 @@
+package connstat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// proxyDialContext returns a DialContext-compatible function that, for
+// every dial, first connects to proxyURL (reporting that as ProxyDial),
+// then tunnels on to the original address by issuing an HTTP CONNECT
+// ("http"/"https" schemes) or performing a SOCKS5 handshake ("socks5"),
+// reporting that as ProxyConnect. The returned net.Conn is otherwise
+// indistinguishable from a direct connection to the origin, so the caller
+// (e.g. http.Transport) can layer TLS on top exactly as it would without a
+// proxy. insecure disables TLS certificate verification when proxyURL's
+// scheme is "https", mirroring Request.Insecure for the main transport.
+func (p *Prober) proxyDialContext(proxyURL *url.URL, insecure bool, report func(dial, connect Timings, err error)) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		base := time.Now()
+
+		dialStart := time.Now()
+		conn, err := p.Dialer.DialContext(ctx, network, proxyURL.Host)
+		if err == nil && proxyURL.Scheme == "https" {
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), InsecureSkipVerify: insecure})
+			if hsErr := tlsConn.HandshakeContext(ctx); hsErr != nil {
+				conn.Close()
+				err = hsErr
+			} else {
+				conn = tlsConn
+			}
+		}
+
+		dial := Timings{Operation: FormatDuration(time.Since(dialStart)), Total: FormatDuration(time.Since(base))}
+		if err != nil {
+			report(dial, Timings{}, err)
+			return nil, err
+		}
+
+		// The CONNECT/SOCKS5 handshake below is plain blocking net.Conn
+		// I/O with no knowledge of ctx. Closing conn when ctx is done
+		// unblocks it, same as the dial and TLS handshake above.
+		watchDone := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+			case <-watchDone:
+			}
+		}()
+
+		connectStart := time.Now()
+		var tunnelled net.Conn
+		if proxyURL.Scheme == "socks5" {
+			tunnelled, err = socks5Connect(conn, address, proxyURL.User)
+		} else {
+			tunnelled, err = httpConnect(conn, address, proxyURL.User)
+		}
+		close(watchDone)
+		connect := Timings{Operation: FormatDuration(time.Since(connectStart)), Total: FormatDuration(time.Since(base))}
+
+		if err != nil {
+			conn.Close()
+			report(dial, connect, err)
+			return nil, err
+		}
+
+		report(dial, connect, nil)
+		return tunnelled, nil
+	}
+}
+
+// httpConnect issues an HTTP CONNECT for address over conn, which must
+// already be connected to the proxy, and returns conn wrapped so that any
+// response bytes buffered while reading the CONNECT reply are not lost.
+func httpConnect(conn net.Conn, address string, auth *url.Userinfo) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if auth != nil {
+		password, _ := auth.Password()
+		creds := base64.StdEncoding.EncodeToString([]byte(auth.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+creds)
+	}
+
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("connstat: proxy CONNECT failed: %s", res.Status)
+	}
+
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn lets an http.Response's bufio.Reader keep any bytes it
+// over-read from the wire, replaying them before further reads from Conn.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// socks5Connect performs a RFC 1928 SOCKS5 handshake over conn, which must
+// already be connected to the proxy, tunnelling on to address.
+func socks5Connect(conn net.Conn, address string, auth *url.Userinfo) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := []byte{0x00}
+	if auth != nil {
+		methods = append(methods, 0x02)
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return nil, err
+	}
+
+	greeting := make([]byte, 2)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return nil, err
+	}
+	if greeting[0] != 0x05 {
+		return nil, fmt.Errorf("connstat: unexpected SOCKS version %d", greeting[0])
+	}
+
+	switch greeting[1] {
+	case 0x00:
+		// No authentication required.
+	case 0x02:
+		if auth == nil {
+			return nil, fmt.Errorf("connstat: proxy requires a SOCKS5 username and password")
+		}
+		if err := socks5Authenticate(conn, auth); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("connstat: proxy rejected all SOCKS5 authentication methods")
+	}
+
+	req := new(bytes.Buffer)
+	req.Write([]byte{0x05, 0x01, 0x00})
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req.WriteByte(0x01)
+			req.Write(ip4)
+		} else {
+			req.WriteByte(0x04)
+			req.Write(ip.To16())
+		}
+	} else {
+		req.WriteByte(0x03)
+		req.WriteByte(byte(len(host)))
+		req.WriteString(host)
+	}
+
+	portBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(portBytes, uint16(port))
+	req.Write(portBytes)
+
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return conn, readSocks5Reply(conn)
+}
+
+func socks5Authenticate(conn net.Conn, auth *url.Userinfo) error {
+	username := auth.Username()
+	password, _ := auth.Password()
+
+	req := new(bytes.Buffer)
+	req.WriteByte(0x01)
+	req.WriteByte(byte(len(username)))
+	req.WriteString(username)
+	req.WriteByte(byte(len(password)))
+	req.WriteString(password)
+
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return err
+	}
+
+	resp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if resp[1] != 0x00 {
+		return fmt.Errorf("connstat: SOCKS5 authentication failed")
+	}
+
+	return nil
+}
+
+// readSocks5Reply reads and validates the server's reply to a SOCKS5
+// CONNECT request, discarding the bound address it carries.
+func readSocks5Reply(conn net.Conn) error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		return fmt.Errorf("connstat: SOCKS5 connect failed: reply code %d", header[1])
+	}
+
+	var addrLen int
+	switch header[3] {
+	case 0x01:
+		addrLen = net.IPv4len
+	case 0x04:
+		addrLen = net.IPv6len
+	case 0x03:
+		lenByte := make([]byte, 1)
+		if _, err := io.ReadFull(conn, lenByte); err != nil {
+			return err
+		}
+		addrLen = int(lenByte[0])
+	default:
+		return fmt.Errorf("connstat: unknown SOCKS5 address type %d", header[3])
+	}
+
+	_, err := io.ReadFull(conn, make([]byte, addrLen+2)) // address + port
+	return err
+}
@@ -0,0 +1,103 @@
+package connstat
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Request describes a single probe to perform.
+type Request struct {
+	// URL is the target to probe, e.g. "https://example.com/".
+	URL string
+
+	// Method is the HTTP method to use. Defaults to http.MethodGet.
+	Method string
+
+	// Header holds any additional request headers to send.
+	Header http.Header
+
+	// Body, if non-empty, is sent as the request body.
+	Body string
+
+	// Timeout bounds the entire probe, including DNS lookup, connect, TLS
+	// handshake and the round trip. Zero means the context's deadline (if
+	// any) applies and no additional timeout is imposed.
+	Timeout time.Duration
+
+	// FollowRedirects controls whether the underlying client follows HTTP
+	// redirects or returns the first response it receives.
+	FollowRedirects bool
+
+	// Insecure disables TLS certificate verification.
+	Insecure bool
+
+	// Subprotocols lists the WebSocket subprotocols to offer via
+	// Sec-WebSocket-Protocol. Only used when URL has a ws:// or wss://
+	// scheme.
+	Subprotocols []string
+
+	// Pings is the number of WebSocket ping frames to send after a
+	// successful Upgrade handshake, recording the pong round-trip time for
+	// each. Only used when URL has a ws:// or wss:// scheme.
+	Pings int
+
+	// HappyEyeballs configures dual-stack dial racing for this probe. Nil
+	// leaves net.Dialer's own defaults in place.
+	HappyEyeballs *HappyEyeballs
+
+	// ProxyURL, if non-empty, routes this probe through the named proxy
+	// instead of dialing the origin directly. The scheme selects the
+	// tunnelling method: "http" and "https" issue an HTTP CONNECT over the
+	// (optionally TLS-wrapped) connection to the proxy; "socks5" performs a
+	// SOCKS5 (RFC 1928) handshake. Userinfo in the URL is sent as
+	// Proxy-Authorization (http/https) or a SOCKS5 username/password.
+	// Ignored for ws://, wss:// and HTTP/3 probes, which always dial the
+	// origin directly.
+	ProxyURL string
+
+	// HTTP2 forces the TLS ALPN offer to include "h2" and requires the
+	// negotiated protocol to be HTTP/2, failing the probe if the server
+	// falls back to HTTP/1.1. Ignored for ws://, wss:// and HTTP/3 probes.
+	HTTP2 bool
+
+	// HTTP3 probes the target over HTTP/3 (QUIC) instead of TCP, using
+	// Dialer.DialPacket over UDP in place of Dialer.DialContext. httptrace's hooks
+	// never fire for QUIC, so TestResults.QUICHandshake replaces
+	// TLSHandshake and Connect as the relevant phase timing.
+	HTTP3 bool
+
+	// AltSvc, when set alongside an HTTP/1.1 or HTTP/2 probe, inspects the
+	// response's Alt-Svc header and, if it advertises "h3" for the probed
+	// authority, repeats the request over HTTP/3 and reports the result of
+	// that upgraded probe instead.
+	AltSvc bool
+}
+
+// httpRequest builds the *http.Request that Probe sends for r.
+func (r *Request) httpRequest(ctx context.Context) (*http.Request, error) {
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if r.Body != "" {
+		body = strings.NewReader(r.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range r.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,29 @@
+package connstat
+
+// TLS describes the negotiated TLS connection state for a probed response.
+type TLS struct {
+	Version            uint16 `json:"version"`
+	HandshakeComplete  bool   `json:"handshake_complete"`
+	CipherSuite        string `json:"cipher_suite"`
+	NegotiatedProtocol string `json:"negotiated_protocol,omitempty"`
+}
+
+// Response holds the HTTP response returned by a probe.
+type Response struct {
+	Status        string `json:"status"`
+	Protocol      string `json:"protocol"`
+	ContentLength int64  `json:"content_length"`
+	ContentType   string `json:"content_type"`
+	Body          []byte `json:"body"`
+	TLS           *TLS   `json:"tls,omitempty"`
+
+	// Subprotocol and Extensions are populated for WebSocket probes from
+	// the Sec-WebSocket-Protocol and Sec-WebSocket-Extensions response
+	// headers negotiated during the Upgrade handshake.
+	Subprotocol string   `json:"subprotocol,omitempty"`
+	Extensions  []string `json:"extensions,omitempty"`
+
+	// Pings holds the round-trip time of each WebSocket ping/pong exchange
+	// requested via Request.Pings.
+	Pings []FormatDuration `json:"pings,omitempty"`
+}
@@ -0,0 +1,138 @@
+package connstat
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// PhaseSummary holds percentile and dispersion statistics, in nanoseconds,
+// for a single timing phase across a batch of probes.
+type PhaseSummary struct {
+	P50    FormatDuration `json:"p50"`
+	P90    FormatDuration `json:"p90"`
+	P99    FormatDuration `json:"p99"`
+	Min    FormatDuration `json:"min"`
+	Max    FormatDuration `json:"max"`
+	StdDev FormatDuration `json:"stddev"`
+}
+
+// Summary aggregates a batch of TestResults into per-phase percentile and
+// dispersion statistics, plus an overall success rate.
+type Summary struct {
+	Count       int     `json:"count"`
+	SuccessRate float64 `json:"success_rate"`
+
+	DNSLookup    PhaseSummary `json:"dns_lookup"`
+	Connect      PhaseSummary `json:"connect"`
+	TLSHandshake PhaseSummary `json:"tls_handshake"`
+	FirstByte    PhaseSummary `json:"first_byte"`
+	Total        PhaseSummary `json:"total"`
+}
+
+// Summarize computes a Summary across results. Entries with a nil
+// TestResults (e.g. a probe that failed before a request could be built)
+// are ignored for the purposes of phase statistics but still counted
+// against SuccessRate.
+func Summarize(results []*TestResults) Summary {
+	summary := Summary{Count: len(results)}
+	if len(results) == 0 {
+		return summary
+	}
+
+	var (
+		successes    int
+		dnsLookups   []time.Duration
+		connects     []time.Duration
+		tlsHandshake []time.Duration
+		firstBytes   []time.Duration
+		totals       []time.Duration
+	)
+
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		if r.Error == "" {
+			successes++
+		}
+
+		// A probe that failed partway through never populated the phases
+		// after the one it failed in, leaving them at their zero value; feeding
+		// those in would drag percentiles and min toward zero, so only
+		// count a phase a probe actually reached.
+		if d := time.Duration(r.DNSLookup.Operation); d > 0 {
+			dnsLookups = append(dnsLookups, d)
+		}
+		if d := time.Duration(r.Connect.Operation); d > 0 {
+			connects = append(connects, d)
+		}
+		if d := time.Duration(r.TLSHandshake.Operation); d > 0 {
+			tlsHandshake = append(tlsHandshake, d)
+		}
+		if d := time.Duration(r.FirstByte); d > 0 {
+			firstBytes = append(firstBytes, d)
+		}
+		if d := time.Duration(r.Total); d > 0 {
+			totals = append(totals, d)
+		}
+	}
+
+	summary.SuccessRate = float64(successes) / float64(len(results))
+	summary.DNSLookup = summarizePhase(dnsLookups)
+	summary.Connect = summarizePhase(connects)
+	summary.TLSHandshake = summarizePhase(tlsHandshake)
+	summary.FirstByte = summarizePhase(firstBytes)
+	summary.Total = summarizePhase(totals)
+
+	return summary
+}
+
+func summarizePhase(durations []time.Duration) PhaseSummary {
+	if len(durations) == 0 {
+		return PhaseSummary{}
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum float64
+	for _, d := range sorted {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(sorted))
+
+	var variance float64
+	for _, d := range sorted {
+		diff := float64(d) - mean
+		variance += diff * diff
+	}
+	variance /= float64(len(sorted))
+
+	return PhaseSummary{
+		P50:    FormatDuration(percentile(sorted, 50)),
+		P90:    FormatDuration(percentile(sorted, 90)),
+		P99:    FormatDuration(percentile(sorted, 99)),
+		Min:    FormatDuration(sorted[0]),
+		Max:    FormatDuration(sorted[len(sorted)-1]),
+		StdDev: FormatDuration(time.Duration(math.Sqrt(variance))),
+	}
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted, which must
+// already be sorted ascending.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	rank := int(math.Ceil(float64(p)/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+
+	return sorted[rank]
+}
@@ -0,0 +1,85 @@
+package connstat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeExcludesUnreachedPhases(t *testing.T) {
+	results := []*TestResults{
+		{
+			DNSLookup:    Timings{Operation: FormatDuration(10)},
+			Connect:      Timings{Operation: FormatDuration(20)},
+			TLSHandshake: Timings{Operation: FormatDuration(30)},
+			FirstByte:    FormatDuration(40),
+			Total:        FormatDuration(100),
+		},
+		{
+			// Failed during the TLS handshake: DNS and Connect succeeded,
+			// but FirstByte and Total were never reached.
+			DNSLookup:    Timings{Operation: FormatDuration(10)},
+			Connect:      Timings{Operation: FormatDuration(20)},
+			TLSHandshake: Timings{},
+			FirstByte:    FormatDuration(0),
+			Total:        FormatDuration(0),
+			Error:        "tls: handshake failure",
+		},
+	}
+
+	summary := Summarize(results)
+
+	if summary.Count != 2 {
+		t.Fatalf("Count = %d, want 2", summary.Count)
+	}
+	if summary.SuccessRate != 0.5 {
+		t.Fatalf("SuccessRate = %v, want 0.5", summary.SuccessRate)
+	}
+
+	// DNS and Connect were reached by both probes.
+	if got := time.Duration(summary.DNSLookup.Min); got != 10 {
+		t.Errorf("DNSLookup.Min = %v, want 10", got)
+	}
+
+	// TLSHandshake, FirstByte and Total were only reached by the first
+	// probe; the second probe's zero values must not drag Min to 0.
+	if got := time.Duration(summary.TLSHandshake.Min); got != 30 {
+		t.Errorf("TLSHandshake.Min = %v, want 30", got)
+	}
+	if got := time.Duration(summary.FirstByte.Min); got != 40 {
+		t.Errorf("FirstByte.Min = %v, want 40", got)
+	}
+	if got := time.Duration(summary.Total.Min); got != 100 {
+		t.Errorf("Total.Min = %v, want 100", got)
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	summary := Summarize(nil)
+	if summary.Count != 0 {
+		t.Fatalf("Count = %d, want 0", summary.Count)
+	}
+	if summary.SuccessRate != 0 {
+		t.Fatalf("SuccessRate = %v, want 0", summary.SuccessRate)
+	}
+}
+
+func TestSummarizePercentiles(t *testing.T) {
+	var results []*TestResults
+	for i := 1; i <= 10; i++ {
+		results = append(results, &TestResults{
+			Total: FormatDuration(time.Duration(i) * time.Millisecond),
+		})
+	}
+
+	summary := Summarize(results)
+
+	if got := time.Duration(summary.Total.Min); got != time.Millisecond {
+		t.Errorf("Total.Min = %v, want 1ms", got)
+	}
+	if got := time.Duration(summary.Total.Max); got != 10*time.Millisecond {
+		t.Errorf("Total.Max = %v, want 10ms", got)
+	}
+	if got := time.Duration(summary.Total.P50); got != 5*time.Millisecond {
+		t.Errorf("Total.P50 = %v, want 5ms", got)
+	}
+}
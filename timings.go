@@ -0,0 +1,81 @@
+package connstat
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FormatDuration marshals a time.Duration as its string representation
+// (e.g. "1.234ms") rather than as a raw integer of nanoseconds.
+type FormatDuration time.Duration
+
+func (d FormatDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Timings reports how long a single phase took, both on its own
+// (Operation) and cumulatively since the probe began (Total).
+type Timings struct {
+	Operation FormatDuration `json:"operation"`
+	Total     FormatDuration `json:"total"`
+}
+
+// ConnectAttempt records a single TCP connect attempt. Happy Eyeballs
+// dual-stack dialing races one attempt per resolved address, so a probe
+// against a dual-stack host produces more than one of these.
+type ConnectAttempt struct {
+	Network   string         `json:"network"`
+	Address   string         `json:"address"`
+	Operation FormatDuration `json:"operation"`
+	Total     FormatDuration `json:"total"`
+	Err       string         `json:"error,omitempty"`
+}
+
+// TestResults is the outcome of a single Prober.Probe call.
+type TestResults struct {
+	Host            string           `json:"host"`
+	Addresses       []string         `json:"addresses,omitempty"`
+	DNSLookup       Timings          `json:"dns_lookup,omitempty"`
+	DNSCacheHit     bool             `json:"dns_cache_hit,omitempty"`
+	DNSCoalesced    bool             `json:"dns_coalesced,omitempty"`
+	DNSStaleUsed    bool             `json:"dns_stale_used,omitempty"`
+	Connect         Timings          `json:"connect"`
+	ConnectAttempts []ConnectAttempt `json:"connect_attempts,omitempty"`
+
+	// ProxyDial times the TCP (and, for an "https" proxy, TLS) connection
+	// to the proxy itself. ProxyConnect times the CONNECT round trip or
+	// SOCKS5 handshake that tunnels on to the origin. Both are zero unless
+	// Request.ProxyURL is set.
+	ProxyDial    Timings `json:"proxy_dial,omitempty"`
+	ProxyConnect Timings `json:"proxy_connect,omitempty"`
+
+	TLSHandshake Timings `json:"tls_handshake,omitempty"`
+
+	// QUICHandshake times an HTTP/3 probe's QUIC handshake, which folds
+	// connection establishment and the TLS 1.3 handshake into a single
+	// round trip (or, with QUICZeroRTT, none at all). It replaces Connect
+	// and TLSHandshake for HTTP/3 probes, since QUIC has no TCP-style
+	// connect phase and httptrace's hooks never fire over it.
+	QUICHandshake Timings `json:"quic_handshake,omitempty"`
+
+	// QUICZeroRTT reports whether an HTTP/3 probe resumed a prior QUIC
+	// connection with 0-RTT, skipping a full handshake round trip.
+	QUICZeroRTT bool `json:"quic_zero_rtt,omitempty"`
+
+	// AltSvcAdvertised is the raw Alt-Svc response header observed on a
+	// probe made with Request.AltSvc set, if any.
+	AltSvcAdvertised string `json:"alt_svc_advertised,omitempty"`
+
+	// AltSvcUpgraded reports whether the advertised Alt-Svc caused a
+	// second probe over HTTP/3, whose result replaced this one.
+	AltSvcUpgraded bool `json:"alt_svc_upgraded,omitempty"`
+
+	// WebSocketUpgrade times the HTTP Upgrade handshake for WebSocket
+	// probes. Zero for plain HTTP(S) probes.
+	WebSocketUpgrade Timings        `json:"websocket_upgrade,omitempty"`
+	FirstByte        FormatDuration `json:"first_byte"`
+	Total            FormatDuration `json:"total"`
+
+	Response *Response `json:"response,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
@@ -0,0 +1,331 @@
+package connstat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tomasbasham/connstat/dnscache"
+)
+
+// websocketGUID is appended to the Sec-WebSocket-Key before hashing to
+// produce the expected Sec-WebSocket-Accept value, per RFC 6455 section 1.3.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes used by the ping/pong probe.
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xa
+)
+
+func isWebSocketURL(target string) bool {
+	u, err := url.Parse(target)
+	return err == nil && (u.Scheme == "ws" || u.Scheme == "wss")
+}
+
+// probeWebSocket performs the DNS -> TCP -> TLS -> HTTP Upgrade handshake
+// for req and, if req.Pings > 0, exchanges that many ping/pong frames
+// before closing the connection with a close frame.
+func (p *Prober) probeWebSocket(ctx context.Context, req *Request) (*TestResults, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.HappyEyeballs != nil {
+		ctx = WithHappyEyeballs(ctx, *req.HappyEyeballs)
+	}
+
+	var results TestResults
+	results.Host = u.Host
+
+	probeStart := time.Now()
+
+	host := u.Host
+	if !strings.Contains(host, ":") {
+		if u.Scheme == "wss" {
+			host += ":443"
+		} else {
+			host += ":80"
+		}
+	}
+
+	tracker := newConnectTracker(probeStart)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		ConnectStart: tracker.connectStart,
+		ConnectDone:  tracker.connectDone,
+	})
+
+	if p.Dialer.DNSCache != nil {
+		ctx = WithClientTrace(ctx, &Trace{
+			DNSCacheDone: func(dnsHost string, result dnscache.Result, duration time.Duration, err error) {
+				results.DNSLookup.Operation = FormatDuration(duration)
+				results.DNSLookup.Total = FormatDuration(time.Since(probeStart))
+				results.Addresses = mapS(result.Addrs, func(addr net.IPAddr) string { return addr.String() })
+				results.DNSCacheHit = result.CacheHit
+				results.DNSCoalesced = result.Coalesced
+				results.DNSStaleUsed = result.StaleUsed
+			},
+		})
+	}
+
+	conn, err := p.Dialer.DialContext(ctx, "tcp", host)
+	results.ConnectAttempts, results.Connect = tracker.result()
+	if err != nil {
+		results.Error = err.Error()
+		return &results, err
+	}
+	defer conn.Close()
+
+	// The Upgrade handshake and ping/pong exchange below are plain
+	// blocking net.Conn I/O with no knowledge of ctx, unlike
+	// tls.Conn.HandshakeContext. Closing conn when ctx is done unblocks
+	// them the same way, so req.Timeout and caller cancellation bound the
+	// whole probe rather than just the dial.
+	watchDone := make(chan struct{})
+	defer close(watchDone)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-watchDone:
+		}
+	}()
+
+	if u.Scheme == "wss" {
+		tlsConfig := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: req.Insecure}
+		tlsConn := tls.Client(conn, tlsConfig)
+
+		handshakeStart := time.Now()
+		err = tlsConn.HandshakeContext(ctx)
+		results.TLSHandshake.Operation = FormatDuration(time.Since(handshakeStart))
+		results.TLSHandshake.Total = FormatDuration(time.Since(probeStart))
+		if err != nil {
+			results.Error = err.Error()
+			return &results, err
+		}
+
+		conn = tlsConn
+	}
+
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return &results, err
+	}
+	secWebSocketKey := base64.StdEncoding.EncodeToString(key)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL, nil)
+	if err != nil {
+		return &results, err
+	}
+
+	for k, vs := range req.Header {
+		for _, v := range vs {
+			httpReq.Header.Add(k, v)
+		}
+	}
+	httpReq.Header.Set("Connection", "Upgrade")
+	httpReq.Header.Set("Upgrade", "websocket")
+	httpReq.Header.Set("Sec-WebSocket-Version", "13")
+	httpReq.Header.Set("Sec-WebSocket-Key", secWebSocketKey)
+	if len(req.Subprotocols) > 0 {
+		httpReq.Header.Set("Sec-WebSocket-Protocol", strings.Join(req.Subprotocols, ", "))
+	}
+
+	upgradeStart := time.Now()
+	if err := httpReq.Write(conn); err != nil {
+		results.Error = err.Error()
+		return &results, err
+	}
+
+	br := bufio.NewReader(conn)
+	httpRes, err := http.ReadResponse(br, httpReq)
+	if err != nil {
+		results.Error = err.Error()
+		return &results, err
+	}
+	defer httpRes.Body.Close()
+
+	if httpRes.StatusCode != http.StatusSwitchingProtocols {
+		err = fmt.Errorf("websocket: server responded with %s", httpRes.Status)
+		results.Error = err.Error()
+		return &results, err
+	}
+
+	expectedAccept := acceptKey(secWebSocketKey)
+	if got := httpRes.Header.Get("Sec-WebSocket-Accept"); got != expectedAccept {
+		err = fmt.Errorf("websocket: invalid Sec-WebSocket-Accept %q", got)
+		results.Error = err.Error()
+		return &results, err
+	}
+
+	results.WebSocketUpgrade.Operation = FormatDuration(time.Since(upgradeStart))
+	results.WebSocketUpgrade.Total = FormatDuration(time.Since(probeStart))
+	results.Total = FormatDuration(time.Since(probeStart))
+
+	// br may have buffered bytes the server sent immediately after the 101
+	// response (e.g. a pong reply in the same segment); read through it
+	// rather than conn so the ping/pong exchange below doesn't miss them.
+	conn = &bufferedConn{Conn: conn, r: br}
+
+	response := &Response{
+		Status:      httpRes.Status,
+		Protocol:    httpRes.Proto,
+		Subprotocol: httpRes.Header.Get("Sec-WebSocket-Protocol"),
+	}
+	if ext := httpRes.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		response.Extensions = splitAndTrim(ext, ",")
+	}
+
+	if req.Pings > 0 {
+		pings, err := exchangePings(conn, req.Pings)
+		response.Pings = pings
+		if err != nil {
+			results.Error = err.Error()
+		}
+	}
+
+	_ = writeFrame(conn, opClose, nil)
+	results.Response = response
+
+	return &results, nil
+}
+
+func acceptKey(secWebSocketKey string) string {
+	h := sha1.New()
+	h.Write([]byte(secWebSocketKey))
+	h.Write([]byte(websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func splitAndTrim(s, sep string) []string {
+	parts := strings.Split(s, sep)
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// exchangePings sends count masked ping frames, one at a time, and records
+// the round-trip time to each corresponding pong.
+func exchangePings(conn interface {
+	Read([]byte) (int, error)
+	Write([]byte) (int, error)
+}, count int) ([]FormatDuration, error) {
+	rtts := make([]FormatDuration, 0, count)
+
+	for i := 0; i < count; i++ {
+		payload := []byte(fmt.Sprintf("connstat-%d", i))
+
+		start := time.Now()
+		if err := writeFrame(conn, opPing, payload); err != nil {
+			return rtts, err
+		}
+
+		if _, err := readPong(conn); err != nil {
+			return rtts, err
+		}
+		rtts = append(rtts, FormatDuration(time.Since(start)))
+	}
+
+	return rtts, nil
+}
+
+// writeFrame writes a single, unfragmented, masked client-to-server frame,
+// as required by RFC 6455 section 5.1.
+func writeFrame(w interface{ Write([]byte) (int, error) }, opcode byte, payload []byte) error {
+	var buf bytes.Buffer
+	buf.WriteByte(0x80 | opcode) // FIN + opcode
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		buf.WriteByte(0x80 | byte(length))
+	case length <= 0xffff:
+		buf.WriteByte(0x80 | 126)
+		buf.WriteByte(byte(length >> 8))
+		buf.WriteByte(byte(length))
+	default:
+		return fmt.Errorf("websocket: frame payload too large (%d bytes)", length)
+	}
+
+	buf.Write(mask)
+	for i, b := range payload {
+		buf.WriteByte(b ^ mask[i%4])
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// readPong reads frames from r until a pong frame is seen, returning its
+// payload, or until a close frame is seen, returning an error.
+func readPong(r interface{ Read([]byte) (int, error) }) ([]byte, error) {
+	header := make([]byte, 2)
+	for {
+		if _, err := readFull(r, header); err != nil {
+			return nil, err
+		}
+
+		opcode := header[0] & 0x0f
+		length := int(header[1] & 0x7f)
+
+		switch length {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err := readFull(r, ext); err != nil {
+				return nil, err
+			}
+			length = int(ext[0])<<8 | int(ext[1])
+		case 127:
+			return nil, fmt.Errorf("websocket: unsupported 64-bit frame length")
+		}
+
+		payload := make([]byte, length)
+		if length > 0 {
+			if _, err := readFull(r, payload); err != nil {
+				return nil, err
+			}
+		}
+
+		switch opcode {
+		case opPong:
+			return payload, nil
+		case opClose:
+			return nil, fmt.Errorf("websocket: server closed the connection")
+		}
+	}
+}
+
+func readFull(r interface{ Read([]byte) (int, error) }, buf []byte) (int, error) {
+	total := 0
+	for total < len(buf) {
+		n, err := r.Read(buf[total:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
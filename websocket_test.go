@@ -0,0 +1,203 @@
+package connstat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFrameMasksPayload(t *testing.T) {
+	var buf bytes.Buffer
+	payload := []byte("ping")
+
+	if err := writeFrame(&buf, opPing, payload); err != nil {
+		t.Fatalf("writeFrame: %v", err)
+	}
+
+	data := buf.Bytes()
+	if got, want := data[0], byte(0x80|opPing); got != want {
+		t.Fatalf("first byte = %#x, want %#x", got, want)
+	}
+	if got, want := data[1]&0x7f, byte(len(payload)); got != want {
+		t.Fatalf("length byte = %d, want %d", got, want)
+	}
+	if data[1]&0x80 == 0 {
+		t.Fatal("MASK bit not set, but RFC 6455 requires client frames to be masked")
+	}
+
+	mask := data[2:6]
+	masked := data[6:]
+	decoded := make([]byte, len(masked))
+	for i, b := range masked {
+		decoded[i] = b ^ mask[i%4]
+	}
+	if !bytes.Equal(decoded, payload) {
+		t.Fatalf("decoded payload = %q, want %q", decoded, payload)
+	}
+}
+
+func TestWriteFrameRejectsOversizedPayload(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeFrame(&buf, opText, make([]byte, 0x10000)); err == nil {
+		t.Fatal("writeFrame: want error for payload exceeding 16-bit length, got nil")
+	}
+}
+
+func TestReadPongSkipsIntermediateFrames(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write([]byte{0x80 | opText, 5})
+	buf.WriteString("hello")
+	buf.Write([]byte{0x80 | opPong, 2})
+	buf.WriteString("ok")
+
+	payload, err := readPong(&buf)
+	if err != nil {
+		t.Fatalf("readPong: %v", err)
+	}
+	if string(payload) != "ok" {
+		t.Fatalf("payload = %q, want %q", payload, "ok")
+	}
+}
+
+func TestReadPongExtendedLength(t *testing.T) {
+	payload := bytes.Repeat([]byte{'x'}, 200)
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0x80 | opPong, 126, 0x00, 0xc8}) // length 200
+	buf.Write(payload)
+
+	got, err := readPong(&buf)
+	if err != nil {
+		t.Fatalf("readPong: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("payload length = %d, want %d", len(got), len(payload))
+	}
+}
+
+func TestReadPongClose(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write([]byte{0x80 | opClose, 0})
+
+	if _, err := readPong(&buf); err == nil {
+		t.Fatal("readPong: want error when the server sends a close frame, got nil")
+	}
+}
+
+func TestAcceptKeyKnownVector(t *testing.T) {
+	// Example from RFC 6455 section 1.3.
+	got := acceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	want := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+	if got != want {
+		t.Fatalf("acceptKey = %q, want %q", got, want)
+	}
+}
+
+func TestSplitAndTrim(t *testing.T) {
+	got := splitAndTrim(" permessage-deflate, client_max_window_bits ", ",")
+	want := []string{"permessage-deflate", "client_max_window_bits"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("splitAndTrim = %v, want %v", got, want)
+	}
+}
+
+// TestProbeWebSocketPongBundledWithUpgradeResponse reproduces a server that
+// writes the 101 Upgrade response and a pong frame in a single TCP write, as
+// can happen when the two are flushed together. The pong must still be
+// found even though the Upgrade response was read through a buffered
+// reader that may have over-read into the pong bytes.
+func TestProbeWebSocketPongBundledWithUpgradeResponse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		httpReq, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		accept := acceptKey(httpReq.Header.Get("Sec-WebSocket-Key"))
+
+		var buf bytes.Buffer
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+		buf.WriteString("Upgrade: websocket\r\n")
+		buf.WriteString("Connection: Upgrade\r\n")
+		buf.WriteString("Sec-WebSocket-Accept: " + accept + "\r\n\r\n")
+		// Bundle a pong frame into the very same write as the 101 response.
+		_ = writeServerFrame(&buf, opPong, []byte("ok"))
+
+		if _, err := conn.Write(buf.Bytes()); err != nil {
+			return
+		}
+
+		// Keep the connection open, discarding the ping and close frames the
+		// client sends, until the client closes it at the end of the probe.
+		drainUntilClosed(conn)
+	}()
+
+	req := &Request{
+		URL:     "ws://" + ln.Addr().String() + "/",
+		Pings:   1,
+		Timeout: 2 * time.Second,
+	}
+
+	p := New()
+	results, err := p.Probe(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Probe: %v", err)
+	}
+	if results.Error != "" {
+		t.Fatalf("results.Error = %q, want empty", results.Error)
+	}
+	if results.Response == nil || len(results.Response.Pings) != 1 {
+		t.Fatalf("Response.Pings = %v, want 1 recorded RTT", results.Response)
+	}
+}
+
+// writeServerFrame writes a single unmasked server-to-client frame, as
+// RFC 6455 requires (only client frames are masked).
+func writeServerFrame(w interface{ Write([]byte) (int, error) }, opcode byte, payload []byte) error {
+	buf := []byte{0x80 | opcode, byte(len(payload))}
+	buf = append(buf, payload...)
+	_, err := w.Write(buf)
+	return err
+}
+
+// drainUntilClosed reads and discards from conn until it's closed or errors,
+// keeping the server side of the test connection open for the client's
+// subsequent reads/writes.
+func drainUntilClosed(conn net.Conn) {
+	buf := make([]byte, 512)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+func TestIsWebSocketURL(t *testing.T) {
+	cases := map[string]bool{
+		"ws://example.com":    true,
+		"wss://example.com":   true,
+		"https://example.com": false,
+		"://bad-url":          false,
+	}
+	for url, want := range cases {
+		if got := isWebSocketURL(url); got != want {
+			t.Errorf("isWebSocketURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}